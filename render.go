@@ -21,13 +21,14 @@ import (
 	"encoding/xml"
 	"fmt"
 	"html/template"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	ttemplate "text/template"
+	"text/template/parse"
 	"time"
 
 	"github.com/Unknwon/macaron/bpool"
@@ -38,9 +39,11 @@ const (
 	ContentLength  = "Content-Length"
 	ContentBinary  = "application/octet-stream"
 	ContentJSON    = "application/json"
+	ContentJSONP   = "application/javascript"
 	ContentHTML    = "text/html"
 	ContentXHTML   = "application/xhtml+xml"
 	ContentXML     = "text/xml"
+	ContentText    = "text/plain"
 	defaultCharset = "UTF-8"
 )
 
@@ -94,6 +97,74 @@ type (
 		PrefixXML []byte
 		// Allows changing of output to XHTML instead of HTML. Default is "text/html"
 		HTMLContentType string
+		// Streams JSON responses through json.Encoder instead of buffering the
+		// whole payload in memory first. Default is false.
+		StreamingJSON bool
+		// Unescapes HTML characters "&<>" which Go's JSON encoder escapes to
+		// Unicode escape sequences (a backslash, "u", then 0026, 003c or
+		// 003e) by default. Default is false.
+		UnEscapeHTML bool
+		// Disables the automatic call to http.Error when a render call fails
+		// to marshal JSON/XML or execute a template; the failed call then
+		// writes nothing further to the response. JSON, JSONP, XML, HTML,
+		// HTMLSet and Format do not return the error. With StreamingJSON the
+		// status and Content-Type are already written when encoding fails.
+		// Default is false.
+		DisableHTTPErrorRendering bool
+		// FileSystem is used to walk and read template files. Default is an
+		// implementation built from Asset and AssetNames when both are set,
+		// otherwise a disk-backed implementation rooted at Directory.
+		FileSystem FileSystem
+		// Asset loads the contents of a single template by name. Used together
+		// with AssetNames to back FileSystem with in-binary assets such as
+		// those produced by go-bindata or embed.FS. Ignored if FileSystem is set.
+		Asset func(name string) ([]byte, error)
+		// AssetNames returns the names of all available assets. See Asset.
+		AssetNames func() []string
+		// OutputFormats maps template file extensions to named output
+		// formats. Extensions not listed here are parsed as HTML. Used by
+		// Render.Format to pick the right engine and Content-Type.
+		OutputFormats []OutputFormat
+		// RequirePartials causes rendering to fail before execution, with an
+		// error naming the missing partials, when the rendered template or
+		// its layout references a {{template "name"}} that isn't defined in
+		// the set. It is checked by HTML, HTMLSet, HTMLString and
+		// HTMLSetString; Format does not check it.
+		RequirePartials bool
+		// HTMLTemplateOption is passed to html/template's Option, e.g.
+		// "missingkey=error" or "missingkey=zero" for strict rendering.
+		HTMLTemplateOption []string
+	}
+
+	// OutputFormat describes a named output format a template set can
+	// render, following the technique Hugo uses to support both HTML and
+	// plain-text template sets side by side.
+	OutputFormat struct {
+		// Name identifies the format, passed as the formatName argument to
+		// Render.Format.
+		Name string
+		// Extension is the template file extension this format applies to,
+		// e.g. ".json" or ".csv".
+		Extension string
+		// ContentType is written as the Content-Type header when this
+		// format is rendered.
+		ContentType string
+		// IsPlainText parses matching templates with text/template instead
+		// of html/template, disabling HTML auto-escaping.
+		IsPlainText bool
+	}
+
+	// FileSystem is the interface Render uses to discover and read template
+	// files, allowing templates to be served from disk, embed.FS, go-bindata,
+	// or any other source.
+	FileSystem interface {
+		// Walk calls walkFn once for every template file found, with path
+		// relative to the template root and using "/" as separator.
+		Walk(walkFn func(path string) error) error
+		// ReadFile returns the contents of the file at path.
+		ReadFile(path string) ([]byte, error)
+		// Exists reports whether the file at path exists.
+		Exists(path string) bool
 	}
 
 	// HTMLOptions is a struct for overriding some rendering Options for specific HTML call
@@ -109,12 +180,15 @@ type Render interface {
 
 	JSON(int, interface{})
 	JSONString(interface{}) (string, error)
+	JSONP(int, string, interface{})
+	Text(int, string)
 	RawData(int, []byte)
 	RenderData(int, []byte)
 	HTML(int, string, interface{}, ...HTMLOptions)
 	HTMLSet(int, string, string, interface{}, ...HTMLOptions)
 	HTMLString(string, interface{}, ...HTMLOptions) (string, error)
 	HTMLSetString(string, string, interface{}, ...HTMLOptions) (string, error)
+	Format(int, string, string, interface{})
 	XML(int, interface{})
 	Error(int, ...string)
 	Status(int)
@@ -126,12 +200,96 @@ const (
 	_DEFAULT_TPL_SET_NAME = "DEFAULT"
 )
 
+// templateSet holds the compiled templates of a single template set, split
+// by engine: HTML files compile into HTML, everything matching a plain-text
+// OutputFormat compiles into Text.
+type templateSet struct {
+	HTML *template.Template
+	Text *ttemplate.Template
+	// MissingPartials maps a template name to the names of the partials it
+	// references via {{template "name"}} that aren't defined anywhere in
+	// HTML. Computed once at compile time so RequirePartials checks are cheap
+	// at render time.
+	MissingPartials map[string][]string
+}
+
+// missingPartials walks every named template in t and reports, for each
+// template that references one, the names of {{template "name"}} partials
+// that aren't defined in t.
+func missingPartials(t *template.Template) map[string][]string {
+	missing := make(map[string][]string)
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree == nil {
+			continue
+		}
+		var names []string
+		for _, name := range referencedTemplates(tmpl.Tree.Root) {
+			if t.Lookup(name) == nil {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			missing[tmpl.Name()] = names
+		}
+	}
+	return missing
+}
+
+// referencedTemplates returns the names passed to every {{template "name"}}
+// action found anywhere within the given node tree.
+func referencedTemplates(node parse.Node) []string {
+	var names []string
+	switch n := node.(type) {
+	case *parse.TemplateNode:
+		names = append(names, n.Name)
+	case *parse.ListNode:
+		if n != nil {
+			for _, child := range n.Nodes {
+				names = append(names, referencedTemplates(child)...)
+			}
+		}
+	case *parse.IfNode:
+		names = append(names, referencedTemplates(n.List)...)
+		names = append(names, referencedTemplates(n.ElseList)...)
+	case *parse.WithNode:
+		names = append(names, referencedTemplates(n.List)...)
+		names = append(names, referencedTemplates(n.ElseList)...)
+	case *parse.RangeNode:
+		names = append(names, referencedTemplates(n.List)...)
+		names = append(names, referencedTemplates(n.ElseList)...)
+	}
+	return names
+}
+
 var (
-	tplSets    = make(map[string]*template.Template)
+	tplSets    = make(map[string]*templateSet)
 	tplSetOpts = make(map[string]*RenderOptions)
 	lock       sync.RWMutex
 )
 
+// formatByExt returns the OutputFormat registered for the given template
+// file extension, or nil if none matches (in which case the file is treated
+// as HTML).
+func (opt *RenderOptions) formatByExt(ext string) *OutputFormat {
+	for i := range opt.OutputFormats {
+		if opt.OutputFormats[i].Extension == ext {
+			return &opt.OutputFormats[i]
+		}
+	}
+	return nil
+}
+
+// formatByName returns the OutputFormat registered under the given name, or
+// nil if none matches (in which case HTML defaults apply).
+func (opt *RenderOptions) formatByName(name string) *OutputFormat {
+	for i := range opt.OutputFormats {
+		if opt.OutputFormats[i].Name == name {
+			return &opt.OutputFormats[i]
+		}
+	}
+	return nil
+}
+
 func prepareOptions(options []RenderOptions) *RenderOptions {
 	var opt RenderOptions
 	if len(options) > 0 {
@@ -151,6 +309,13 @@ func prepareOptions(options []RenderOptions) *RenderOptions {
 	if len(opt.HTMLContentType) == 0 {
 		opt.HTMLContentType = ContentHTML
 	}
+	if opt.FileSystem == nil {
+		if opt.Asset != nil && opt.AssetNames != nil {
+			opt.FileSystem = &assetFileSystem{asset: opt.Asset, names: opt.AssetNames}
+		} else {
+			opt.FileSystem = &dirFileSystem{dir: opt.Directory}
+		}
+	}
 
 	lock.RLock()
 	defer lock.RUnlock()
@@ -174,39 +339,105 @@ func getExt(s string) string {
 	return "." + strings.Join(strings.Split(s, ".")[1:], ".")
 }
 
-func compile(options *RenderOptions) {
-	dir := options.Directory
-	t := template.New(dir)
-	t.Delims(options.Delims.Left, options.Delims.Right)
-	// Parse an initial template in case we don't have any.
-	template.Must(t.Parse("Macaron"))
+// dirFileSystem is the default FileSystem, backed by files on disk.
+type dirFileSystem struct {
+	dir string
+}
 
-	if err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		r, err := filepath.Rel(dir, path)
+func (fs *dirFileSystem) Walk(walkFn func(path string) error) error {
+	return filepath.Walk(fs.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		r, err := filepath.Rel(fs.dir, path)
 		if err != nil {
 			return err
 		}
+		return walkFn(filepath.ToSlash(r))
+	})
+}
+
+func (fs *dirFileSystem) ReadFile(path string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(fs.dir, filepath.FromSlash(path)))
+}
+
+func (fs *dirFileSystem) Exists(path string) bool {
+	_, err := os.Stat(filepath.Join(fs.dir, filepath.FromSlash(path)))
+	return err == nil
+}
+
+// assetFileSystem is a FileSystem backed by an Asset/AssetNames pair, e.g.
+// as produced by go-bindata or wrapping an embed.FS.
+type assetFileSystem struct {
+	asset func(name string) ([]byte, error)
+	names func() []string
+}
+
+func (fs *assetFileSystem) Walk(walkFn func(path string) error) error {
+	for _, name := range fs.names() {
+		if err := walkFn(filepath.ToSlash(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fs *assetFileSystem) ReadFile(path string) ([]byte, error) {
+	return fs.asset(path)
+}
 
+func (fs *assetFileSystem) Exists(path string) bool {
+	_, err := fs.asset(path)
+	return err == nil
+}
+
+func compile(options *RenderOptions) {
+	dir := options.Directory
+	htmlSet := template.New(dir)
+	htmlSet.Delims(options.Delims.Left, options.Delims.Right)
+	// Parse an initial template in case we don't have any.
+	template.Must(htmlSet.Parse("Macaron"))
+
+	textSet := ttemplate.New(dir)
+	textSet.Delims(options.Delims.Left, options.Delims.Right)
+	ttemplate.Must(textSet.Parse("Macaron"))
+
+	if err := options.FileSystem.Walk(func(r string) error {
 		ext := getExt(r)
 
 		for _, extension := range options.Extensions {
 			if ext == extension {
 
-				buf, err := ioutil.ReadFile(path)
+				buf, err := options.FileSystem.ReadFile(r)
 				if err != nil {
 					panic(err)
 				}
 
 				name := (r[0 : len(r)-len(ext)])
-				tmpl := t.New(filepath.ToSlash(name))
 
-				// add our funcmaps
-				for _, funcs := range options.Funcs {
-					tmpl.Funcs(funcs)
+				// Plain-text output formats (e.g. JSON, CSV, sitemap XML)
+				// parse into the text/template set so values aren't HTML
+				// escaped; everything else stays html/template as before.
+				if format := options.formatByExt(ext); format != nil && format.IsPlainText {
+					tmpl := textSet.New(filepath.ToSlash(name))
+					for _, funcs := range options.Funcs {
+						tmpl.Funcs(funcs)
+					}
+					ttemplate.Must(tmpl.Parse(string(buf)))
+				} else {
+					tmpl := htmlSet.New(filepath.ToSlash(name))
+
+					// add our funcmaps
+					for _, funcs := range options.Funcs {
+						tmpl.Funcs(funcs)
+					}
+
+					// Bomb out if parse fails. We don't want any silent server starts.
+					template.Must(tmpl.Funcs(helperFuncs).Parse(string(buf)))
 				}
-
-				// Bomb out if parse fails. We don't want any silent server starts.
-				template.Must(tmpl.Funcs(helperFuncs).Parse(string(buf)))
 				break
 			}
 		}
@@ -216,10 +447,14 @@ func compile(options *RenderOptions) {
 		panic("fail to walk templates directory: " + err.Error())
 	}
 
+	if len(options.HTMLTemplateOption) > 0 {
+		htmlSet.Option(options.HTMLTemplateOption...)
+	}
+
 	lock.Lock()
 	defer lock.Unlock()
 
-	tplSets[options.Name] = t
+	tplSets[options.Name] = &templateSet{HTML: htmlSet, Text: textSet, MissingPartials: missingPartials(htmlSet)}
 }
 
 // Renderer is a Middleware that maps a macaron.Render service into the Macaron handler chain.
@@ -266,7 +501,41 @@ func (r *TplRender) RW() http.ResponseWriter {
 	return r.ResponseWriter
 }
 
+// errorHandler writes the given error via http.Error unless the user has
+// opted out via DisableHTTPErrorRendering, in which case the response is
+// left untouched.
+func (r *TplRender) errorHandler(err error, status int) {
+	if r.Opt.DisableHTTPErrorRendering {
+		return
+	}
+	http.Error(r, err.Error(), status)
+}
+
+func unEscapeHTML(json []byte) []byte {
+	json = bytes.Replace(json, []byte("\\u0026"), []byte("&"), -1)
+	json = bytes.Replace(json, []byte("\\u003c"), []byte("<"), -1)
+	json = bytes.Replace(json, []byte("\\u003e"), []byte(">"), -1)
+	return json
+}
+
 func (r *TplRender) JSON(status int, v interface{}) {
+	if r.Opt.StreamingJSON {
+		r.Header().Set(ContentType, ContentJSON+r.CompiledCharset)
+		r.WriteHeader(status)
+		if len(r.Opt.PrefixJSON) > 0 {
+			r.Write(r.Opt.PrefixJSON)
+		}
+		enc := json.NewEncoder(r)
+		enc.SetEscapeHTML(!r.Opt.UnEscapeHTML)
+		if r.Opt.IndentJSON {
+			enc.SetIndent("", "  ")
+		}
+		if err := enc.Encode(v); err != nil {
+			r.errorHandler(err, 500)
+		}
+		return
+	}
+
 	var result []byte
 	var err error
 	if r.Opt.IndentJSON {
@@ -275,9 +544,12 @@ func (r *TplRender) JSON(status int, v interface{}) {
 		result, err = json.Marshal(v)
 	}
 	if err != nil {
-		http.Error(r, err.Error(), 500)
+		r.errorHandler(err, 500)
 		return
 	}
+	if r.Opt.UnEscapeHTML {
+		result = unEscapeHTML(result)
+	}
 
 	// json rendered fine, write out the result
 	r.Header().Set(ContentType, ContentJSON+r.CompiledCharset)
@@ -288,6 +560,38 @@ func (r *TplRender) JSON(status int, v interface{}) {
 	r.Write(result)
 }
 
+// JSONP marshals the given interface object and wraps it in the given
+// callback, writing the result as "application/javascript".
+func (r *TplRender) JSONP(status int, callback string, v interface{}) {
+	var result []byte
+	var err error
+	if r.Opt.IndentJSON {
+		result, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		result, err = json.Marshal(v)
+	}
+	if err != nil {
+		r.errorHandler(err, 500)
+		return
+	}
+	if r.Opt.UnEscapeHTML {
+		result = unEscapeHTML(result)
+	}
+
+	r.Header().Set(ContentType, ContentJSONP+r.CompiledCharset)
+	r.WriteHeader(status)
+	r.Write([]byte(callback + "("))
+	r.Write(result)
+	r.Write([]byte(");"))
+}
+
+// Text writes the given string as "text/plain".
+func (r *TplRender) Text(status int, v string) {
+	r.Header().Set(ContentType, ContentText+r.CompiledCharset)
+	r.WriteHeader(status)
+	r.Write([]byte(v))
+}
+
 func (r *TplRender) JSONString(v interface{}) (string, error) {
 	var result []byte
 	var err error
@@ -311,7 +615,7 @@ func (r *TplRender) XML(status int, v interface{}) {
 		result, err = xml.Marshal(v)
 	}
 	if err != nil {
-		http.Error(r, err.Error(), 500)
+		r.errorHandler(err, 500)
 		return
 	}
 
@@ -340,17 +644,29 @@ func (r *TplRender) RenderData(status int, v []byte) {
 	r.data(status, ContentHTML, v)
 }
 
-func (r *TplRender) execute(t *template.Template, name string, data interface{}) (*bytes.Buffer, error) {
+// execute runs the named template against a pooled buffer, always returning
+// the buffer to the pool before returning so callers never leak it, on
+// either the success or the error path.
+func (r *TplRender) execute(t *template.Template, name string, data interface{}) (string, error) {
+	buf := bufpool.Get()
+	defer bufpool.Put(buf)
+	err := t.ExecuteTemplate(buf, name, data)
+	return buf.String(), err
+}
+
+func (r *TplRender) executeText(t *ttemplate.Template, name string, data interface{}) (string, error) {
 	buf := bufpool.Get()
-	return buf, t.ExecuteTemplate(buf, name, data)
+	defer bufpool.Put(buf)
+	err := t.ExecuteTemplate(buf, name, data)
+	return buf.String(), err
 }
 
 func (r *TplRender) addYield(t *template.Template, tplName string, data interface{}) {
 	funcs := template.FuncMap{
 		"yield": func() (template.HTML, error) {
-			buf, err := r.execute(t, tplName, data)
+			out, err := r.execute(t, tplName, data)
 			// return safe html here since we are rendering our own template
-			return template.HTML(buf.String()), err
+			return template.HTML(out), err
 		},
 		"current": func() (string, error) {
 			return tplName, nil
@@ -359,7 +675,7 @@ func (r *TplRender) addYield(t *template.Template, tplName string, data interfac
 	t.Funcs(funcs)
 }
 
-func (r *TplRender) renderBytes(setName, tplName string, data interface{}, htmlOpt ...HTMLOptions) (*bytes.Buffer, error) {
+func (r *TplRender) renderBytes(setName, tplName string, data interface{}, htmlOpt ...HTMLOptions) (string, error) {
 	renderOpt := tplSetOpts[setName]
 	if Env == DEV {
 		compile(renderOpt)
@@ -368,24 +684,44 @@ func (r *TplRender) renderBytes(setName, tplName string, data interface{}, htmlO
 	lock.RLock()
 	defer lock.RUnlock()
 
-	t := tplSets[setName]
-	if t == nil {
-		return nil, fmt.Errorf("html/template: template \"%s\" is undefined", tplName)
+	set := tplSets[setName]
+	if set == nil {
+		return "", fmt.Errorf("html/template: template \"%s\" is undefined", tplName)
+	}
+
+	// Clone the compiled set per request: addYield below attaches
+	// request-scoped "yield"/"current" funcs, and mutating the shared
+	// *template.Template directly races with concurrent requests using a
+	// different layout.
+	t, err := set.HTML.Clone()
+	if err != nil {
+		return "", err
+	}
+	// Clone() drops Option settings on older Go releases, so re-apply them.
+	if len(renderOpt.HTMLTemplateOption) > 0 {
+		t.Option(renderOpt.HTMLTemplateOption...)
 	}
 
 	opt := r.prepareHTMLOptions(htmlOpt)
 
+	contentName := tplName
 	if len(opt.Layout) > 0 {
 		r.addYield(t, tplName, data)
 		tplName = opt.Layout
 	}
 
-	out, err := r.execute(t, tplName, data)
-	if err != nil {
-		return nil, err
+	if renderOpt.RequirePartials {
+		// Check both the content template (rendered via yield) and the
+		// layout itself; a layout-only check misses an undefined partial
+		// referenced from the content template.
+		for _, name := range []string{contentName, tplName} {
+			if missing := set.MissingPartials[name]; len(missing) > 0 {
+				return "", fmt.Errorf("html/template: %q references undefined partial(s): %s", name, strings.Join(missing, ", "))
+			}
+		}
 	}
 
-	return out, nil
+	return r.execute(t, tplName, data)
 }
 
 func (r *TplRender) renderHTML(status int, setName, tplName string, data interface{}, htmlOpt ...HTMLOptions) {
@@ -393,15 +729,14 @@ func (r *TplRender) renderHTML(status int, setName, tplName string, data interfa
 
 	out, err := r.renderBytes(setName, tplName, data, htmlOpt...)
 	if err != nil {
-		http.Error(r, err.Error(), http.StatusInternalServerError)
+		r.errorHandler(err, http.StatusInternalServerError)
 		return
 	}
 
 	r.Header().Set(ContentType, r.Opt.HTMLContentType+r.CompiledCharset)
 	r.WriteHeader(status)
 
-	io.Copy(r, out)
-	bufpool.Put(out)
+	r.Write([]byte(out))
 }
 
 func (r *TplRender) HTML(status int, name string, data interface{}, htmlOpt ...HTMLOptions) {
@@ -412,20 +747,66 @@ func (r *TplRender) HTMLSet(status int, setName, tplName string, data interface{
 	r.renderHTML(status, setName, tplName, data, htmlOpt...)
 }
 
-func (r *TplRender) renderHTMLString(setName, tplName string, data interface{}, htmlOpt ...HTMLOptions) (string, error) {
-	if out, err := r.renderBytes(setName, tplName, data, htmlOpt...); err != nil {
-		return "", err
-	} else {
-		return out.String(), nil
-	}
-}
-
 func (r *TplRender) HTMLString(name string, data interface{}, htmlOpt ...HTMLOptions) (string, error) {
-	return r.renderHTMLString(_DEFAULT_TPL_SET_NAME, name, data, htmlOpt...)
+	return r.renderBytes(_DEFAULT_TPL_SET_NAME, name, data, htmlOpt...)
 }
 
 func (r *TplRender) HTMLSetString(setName, tplName string, data interface{}, htmlOpt ...HTMLOptions) (string, error) {
-	return r.renderHTMLString(setName, tplName, data, htmlOpt...)
+	return r.renderBytes(setName, tplName, data, htmlOpt...)
+}
+
+// Format renders tplName from the default template set using the engine and
+// Content-Type registered for formatName in RenderOptions.OutputFormats. An
+// unknown formatName falls back to HTML, matching the zero-value OutputFormat.
+func (r *TplRender) Format(status int, formatName, tplName string, data interface{}) {
+	r.startTime = time.Now()
+
+	renderOpt := tplSetOpts[_DEFAULT_TPL_SET_NAME]
+	if Env == DEV {
+		compile(renderOpt)
+	}
+
+	lock.RLock()
+	defer lock.RUnlock()
+
+	set := tplSets[_DEFAULT_TPL_SET_NAME]
+	if set == nil {
+		r.errorHandler(fmt.Errorf("html/template: template \"%s\" is undefined", tplName), http.StatusInternalServerError)
+		return
+	}
+
+	contentType := r.Opt.HTMLContentType
+	isPlainText := false
+	if format := renderOpt.formatByName(formatName); format != nil {
+		contentType = format.ContentType
+		isPlainText = format.IsPlainText
+	}
+
+	var out string
+	var err error
+	if isPlainText {
+		out, err = r.executeText(set.Text, tplName, data)
+	} else {
+		// Clone before executing: html/template refuses to Clone a template
+		// set that has already executed, and renderBytes depends on
+		// set.HTML staying pristine so it can keep cloning it per request.
+		var t *template.Template
+		t, err = set.HTML.Clone()
+		if err == nil {
+			if len(renderOpt.HTMLTemplateOption) > 0 {
+				t.Option(renderOpt.HTMLTemplateOption...)
+			}
+			out, err = r.execute(t, tplName, data)
+		}
+	}
+	if err != nil {
+		r.errorHandler(err, http.StatusInternalServerError)
+		return
+	}
+
+	r.Header().Set(ContentType, contentType+r.CompiledCharset)
+	r.WriteHeader(status)
+	r.Write([]byte(out))
 }
 
 // Error writes the given HTTP status to the current ResponseWriter
@@ -465,5 +846,8 @@ func (r *TplRender) SetTemplatePath(setName, dir string) {
 	}
 	opt := tplSetOpts[setName]
 	opt.Directory = dir
+	if _, ok := opt.FileSystem.(*dirFileSystem); ok {
+		opt.FileSystem = &dirFileSystem{dir: dir}
+	}
 	compile(opt)
 }
@@ -0,0 +1,97 @@
+// Copyright 2014 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package macaron
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// raceTestAssets backs a template set with two layouts and one content
+// template, entirely in memory so the test doesn't depend on a templates
+// directory on disk.
+var raceTestAssets = map[string][]byte{
+	"content.tmpl": []byte("content-{{.}}"),
+	"layouta.tmpl": []byte("A:{{yield}}"),
+	"layoutb.tmpl": []byte("B:{{yield}}"),
+}
+
+func raceTestOptions() RenderOptions {
+	return RenderOptions{
+		Name: _DEFAULT_TPL_SET_NAME,
+		Asset: func(name string) ([]byte, error) {
+			buf, ok := raceTestAssets[name]
+			if !ok {
+				return nil, fmt.Errorf("asset %q not found", name)
+			}
+			return buf, nil
+		},
+		AssetNames: func() []string {
+			names := make([]string, 0, len(raceTestAssets))
+			for name := range raceTestAssets {
+				names = append(names, name)
+			}
+			return names
+		},
+	}
+}
+
+func newTestTplRender(opt *RenderOptions) *TplRender {
+	return &TplRender{
+		ResponseWriter:  httptest.NewRecorder(),
+		Req:             httptest.NewRequest("GET", "/", nil),
+		Opt:             opt,
+		CompiledCharset: "; charset=UTF-8",
+	}
+}
+
+// TestHTMLConcurrentDifferentLayouts fires concurrent HTML calls against
+// different layouts of the same compiled set. Run with "go test -race" to
+// prove renderBytes's per-request Clone() keeps addYield's Funcs mutation
+// from racing across requests.
+func TestHTMLConcurrentDifferentLayouts(t *testing.T) {
+	opt := prepareOptions([]RenderOptions{raceTestOptions()})
+	compile(opt)
+
+	layouts := []string{"layouta", "layoutb"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		layout := layouts[i%len(layouts)]
+		wg.Add(1)
+		go func(i int, layout string) {
+			defer wg.Done()
+			r := newTestTplRender(opt)
+			r.HTML(200, "content", i, HTMLOptions{Layout: layout})
+		}(i, layout)
+	}
+	wg.Wait()
+}
+
+// BenchmarkHTMLClonePerRequest measures the cost of the per-request
+// template Clone() introduced to make concurrent rendering with different
+// layouts safe.
+func BenchmarkHTMLClonePerRequest(b *testing.B) {
+	opt := prepareOptions([]RenderOptions{raceTestOptions()})
+	compile(opt)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := newTestTplRender(opt)
+		r.HTML(200, "content", i, HTMLOptions{Layout: "layouta"})
+	}
+}